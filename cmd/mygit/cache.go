@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const cacheFilePath = ".git/mygit-cache"
+
+// CacheKey identifies a file by the stat info that would change if its
+// contents did: its absolute path, size, modification time, and mode.
+type CacheKey struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Mode    uint32
+}
+
+// CacheContext maps a file's stat info to the sha1 of its blob object, so
+// createObjectFromFile can skip re-reading and re-hashing unchanged files.
+type CacheContext interface {
+	Get(key CacheKey) (hash string, ok bool)
+	Set(key CacheKey, hash string)
+	Invalidate(path string)
+}
+
+// cacheEntry is the stat info and hash recorded for a single path.
+type cacheEntry struct {
+	Size    int64
+	ModTime int64
+	Mode    uint32
+	Hash    string
+}
+
+func matches(entry cacheEntry, key CacheKey) bool {
+	return entry.Size == key.Size && entry.ModTime == key.ModTime && entry.Mode == key.Mode
+}
+
+// noopCache never hits, used for --no-cache.
+type noopCache struct{}
+
+func (noopCache) Get(CacheKey) (string, bool) { return "", false }
+func (noopCache) Set(CacheKey, string)        {}
+func (noopCache) Invalidate(string)           {}
+
+// memCache is an in-memory CacheContext, handy for tests that shouldn't
+// touch the filesystem.
+type memCache struct {
+	entries map[string]cacheEntry
+}
+
+func newMemCache() *memCache {
+	return &memCache{entries: make(map[string]cacheEntry)}
+}
+
+func (c *memCache) Get(key CacheKey) (string, bool) {
+	entry, ok := c.entries[key.Path]
+	if !ok || !matches(entry, key) {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+func (c *memCache) Set(key CacheKey, hash string) {
+	c.entries[key.Path] = cacheEntry{Size: key.Size, ModTime: key.ModTime, Mode: key.Mode, Hash: hash}
+}
+
+func (c *memCache) Invalidate(path string) {
+	delete(c.entries, path)
+}
+
+// fileCache is a CacheContext persisted as JSON at .git/mygit-cache.
+type fileCache struct {
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// loadFileCache reads the on-disk cache, or returns an empty one if it
+// doesn't exist yet.
+func loadFileCache() (*fileCache, error) {
+	c := &fileCache{entries: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(cacheFilePath)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *fileCache) Get(key CacheKey) (string, bool) {
+	entry, ok := c.entries[key.Path]
+	if !ok {
+		return "", false
+	}
+
+	if !matches(entry, key) {
+		c.Invalidate(key.Path)
+		return "", false
+	}
+
+	return entry.Hash, true
+}
+
+func (c *fileCache) Set(key CacheKey, hash string) {
+	c.entries[key.Path] = cacheEntry{Size: key.Size, ModTime: key.ModTime, Mode: key.Mode, Hash: hash}
+	c.dirty = true
+}
+
+func (c *fileCache) Invalidate(path string) {
+	if _, ok := c.entries[path]; ok {
+		delete(c.entries, path)
+		c.dirty = true
+	}
+}
+
+// Save writes the cache back to .git/mygit-cache, if anything changed.
+func (c *fileCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cacheFilePath, data, 0644)
+}
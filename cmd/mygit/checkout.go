@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkoutOptions controls how restoreTree/restoreBlob behave when a path
+// in the working directory already exists.
+type checkoutOptions struct {
+	Force  bool
+	DryRun bool
+}
+
+// checkout writes a commit or tree's contents into the working directory.
+func checkout(args []string) error {
+	opts := checkoutOptions{}
+	var hash string
+
+	for _, arg := range args {
+		switch arg {
+		case "--force":
+			opts.Force = true
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			hash = arg
+		}
+	}
+
+	if hash == "" {
+		return fmt.Errorf("usage: mygit checkout [--force] [--dry-run] <commit-or-tree-hash>")
+	}
+
+	treeHash, err := resolveTreeHash(hash)
+	if err != nil {
+		return err
+	}
+
+	return restoreTree(treeHash, ".", opts)
+}
+
+// readObject decompresses the object stored at <hash> and splits it into
+// its kind ("blob", "tree", "commit") and body.
+func readObject(hash string) (string, []byte, error) {
+	file, err := os.Open(filePathFromObjectHash(hash))
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	contents, err := decompress(file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	header, body := splitOn(contents, 0)
+	if len(header) == 0 {
+		return "", nil, fmt.Errorf("invalid object %s", hash)
+	}
+
+	kind, _ := splitOn(header, ' ')
+	return string(kind), body, nil
+}
+
+// resolveTreeHash accepts either a tree hash or a commit hash and returns
+// the tree hash to restore.
+func resolveTreeHash(hash string) (string, error) {
+	kind, body, err := readObject(hash)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case "tree":
+		return hash, nil
+	case "commit":
+		c, err := parseCommitObject(body)
+		if err != nil {
+			return "", err
+		}
+		return c.Tree, nil
+	default:
+		return "", fmt.Errorf("object %s is a %s, not a commit or tree", hash, kind)
+	}
+}
+
+// restoreTree recursively walks the tree at <hash>, writing every blob it
+// contains to <destDir> and recreating the directory structure.
+func restoreTree(hash, destDir string, opts checkoutOptions) error {
+	treeFile, err := os.Open(filePathFromObjectHash(hash))
+	if err != nil {
+		return err
+	}
+	defer treeFile.Close()
+
+	contents, err := decompress(treeFile)
+	if err != nil {
+		return err
+	}
+
+	entries, err := parseTreeObject(contents)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Printf("would create directory %s\n", destDir)
+	} else if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(destDir, entry.FileName)
+		entryHash := fmt.Sprintf("%x", entry.ShaHash)
+
+		if entry.Mode == ObjectModeDir {
+			if err := restoreTree(entryHash, path, opts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := restoreBlob(entryHash, path, entry.Mode, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreBlob writes the blob at <hash> to <destPath>, honoring the file
+// mode recorded in the tree entry. It refuses to clobber a locally
+// modified file unless opts.Force is set.
+func restoreBlob(hash, destPath string, mode ObjectMode, opts checkoutOptions) error {
+	kind, body, err := readObject(hash)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "blob":
+	case "chunked-blob":
+		body, err = reassembleChunkedBlob(body)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("object %s is a %s, not a blob", hash, kind)
+	}
+
+	if opts.DryRun {
+		fmt.Printf("would write %s\n", destPath)
+		return nil
+	}
+
+	if !opts.Force {
+		modified, err := fileModified(destPath, hash)
+		if err != nil {
+			return err
+		}
+		if modified {
+			return fmt.Errorf("%s has local modifications, use --force to overwrite", destPath)
+		}
+	}
+
+	if mode == ObjectModeSymlink {
+		os.Remove(destPath)
+		return os.Symlink(string(body), destPath)
+	}
+
+	perm := os.FileMode(0644)
+	if mode == ObjectModeExe {
+		perm = 0755
+	}
+
+	return os.WriteFile(destPath, body, perm)
+}
+
+// fileModified reports whether the file at path already exists and its
+// contents hash to something other than wantHash. A missing file is never
+// considered modified.
+func fileModified(path, wantHash string) (bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	hash, err := hashFile(path, noopCache{}, defaultChunkThreshold)
+	if err != nil {
+		return false, err
+	}
+
+	return hash != wantHash, nil
+}
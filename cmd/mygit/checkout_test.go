@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRestoreTreeNestedDirectories checks that restoreTree (the function
+// clone uses to materialize a fetched tree after unpacking) reconstructs
+// nested directories at their real paths instead of duplicating path
+// segments, which was the symptom of the createTreeFromDir entry-naming
+// bug for any repo with nesting beyond one level.
+func TestRestoreTreeNestedDirectories(t *testing.T) {
+	chdirTempRepo(t)
+	_, relPath, contents := makeNestedFile(t)
+
+	cache, err := loadFileCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := createTreeFromDir(".", cache, defaultChunkThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcObjectsDir, err := filepath.Abs(ObjectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := t.TempDir()
+	if err := os.Chdir(destDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := initRepo(); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyObjectsDir(srcObjectsDir, ObjectsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restoreTree(fmt.Sprintf("%x", tree.ShaHash), ".", checkoutOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(relPath)
+	if err != nil {
+		t.Fatalf("expected file at %s, got error: %v", relPath, err)
+	}
+	if string(got) != string(contents) {
+		t.Fatalf("restored contents = %q, want %q", got, contents)
+	}
+}
+
+// copyObjectsDir copies an object store wholesale, used to share objects
+// between a source and destination repo in tests without a real clone.
+func copyObjectsDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
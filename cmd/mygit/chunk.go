@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// defaultChunkThreshold is the file size above which createObjectFromFile
+	// splits a file into content-defined chunks instead of writing it as a
+	// single blob.
+	defaultChunkThreshold = 4 * 1024 * 1024 // 4 MiB
+
+	chunkWindowSize = 64              // bytes considered when testing for a boundary
+	chunkMinSize    = 512 * 1024      // never emit a chunk smaller than this (except the last)
+	chunkMaxSize    = 8 * 1024 * 1024 // force a boundary if none is found by this size
+	chunkMaskBits   = 20              // low bits of the rolling hash that must be zero; 2^20 gives a ~1 MiB average
+	chunkPolyBase   = uint64(1099511628211)
+)
+
+// chunkEntry records one chunk of a chunked-blob: where it sits in the
+// original file, how large it is, and the sha1 of the blob object storing
+// its bytes.
+type chunkEntry struct {
+	Offset int64
+	Size   int64
+	Hash   [20]byte
+}
+
+// rollingHash maintains a polynomial hash over the last chunkWindowSize
+// bytes seen, so testing for a chunk boundary is O(1) per byte rather than
+// rehashing the whole window every time it slides.
+type rollingHash struct {
+	hash   uint64
+	window [chunkWindowSize]byte
+	filled int
+	pos    int
+	pow    uint64 // chunkPolyBase^(chunkWindowSize-1), used to evict the oldest byte
+}
+
+func newRollingHash() *rollingHash {
+	pow := uint64(1)
+	for i := 0; i < chunkWindowSize-1; i++ {
+		pow *= chunkPolyBase
+	}
+	return &rollingHash{pow: pow}
+}
+
+// roll slides b into the window, evicting the oldest byte once the window
+// is full, and returns the updated hash.
+func (r *rollingHash) roll(b byte) uint64 {
+	if r.filled < chunkWindowSize {
+		r.hash = r.hash*chunkPolyBase + uint64(b)
+		r.window[r.pos] = b
+		r.filled++
+	} else {
+		old := r.window[r.pos]
+		r.hash = (r.hash-uint64(old)*r.pow)*chunkPolyBase + uint64(b)
+		r.window[r.pos] = b
+	}
+	r.pos = (r.pos + 1) % chunkWindowSize
+	return r.hash
+}
+
+// isBoundary reports whether the current hash marks a chunk boundary: the
+// low chunkMaskBits bits of the rolling hash are all zero.
+func (r *rollingHash) isBoundary() bool {
+	return r.filled == chunkWindowSize && r.hash&((1<<chunkMaskBits)-1) == 0
+}
+
+// splitChunks reads all of r and applies a FastCDC-style content-defined
+// chunker: a boundary falls wherever the rolling hash's low bits are zero,
+// subject to a minimum chunk size (so small hash hits don't fragment the
+// file) and a maximum one (so a pathological run without hits still ends).
+func splitChunks(r io.Reader) ([][]byte, error) {
+	br := bufio.NewReader(r)
+	var chunks [][]byte
+	var current bytes.Buffer
+	rh := newRollingHash()
+
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		current.WriteByte(b)
+		rh.roll(b)
+
+		atMin := current.Len() >= chunkMinSize
+		atMax := current.Len() >= chunkMaxSize
+		if (atMin && rh.isBoundary()) || atMax {
+			chunks = append(chunks, append([]byte(nil), current.Bytes()...))
+			current.Reset()
+			rh = newRollingHash()
+		}
+	}
+
+	if current.Len() > 0 {
+		chunks = append(chunks, append([]byte(nil), current.Bytes()...))
+	}
+
+	return chunks, nil
+}
+
+// blobHash hashes data the way `blob <size>\x00<data>` is hashed, without
+// writing anything to disk.
+func blobHash(data []byte) [20]byte {
+	header := fmt.Sprintf("blob %d", len(data))
+	objectBytes := bytes.Join([][]byte{[]byte(header), data}, []byte{0})
+	return sha1.Sum(objectBytes)
+}
+
+// writeBlobObject hashes data as a `blob <size>\x00<data>` object and writes
+// it to disk, returning its sha1.
+func writeBlobObject(data []byte) ([20]byte, error) {
+	header := fmt.Sprintf("blob %d", len(data))
+	objectBytes := bytes.Join([][]byte{[]byte(header), data}, []byte{0})
+	hash := blobHash(data)
+
+	object := &Object{ShaHash: hash[:]}
+	if err := object.writeToDisk(objectBytes); err != nil {
+		return hash, err
+	}
+
+	return hash, nil
+}
+
+// serializeChunkedBlobObject renders a chunked-blob object: the header
+// followed by one 36-byte (offset, size, sha1) triple per chunk, all
+// big-endian like tree.go's own entries.
+func serializeChunkedBlobObject(totalSize int64, entries []chunkEntry) []byte {
+	var body bytes.Buffer
+	for _, entry := range entries {
+		binary.Write(&body, binary.BigEndian, entry.Offset)
+		binary.Write(&body, binary.BigEndian, entry.Size)
+		body.Write(entry.Hash[:])
+	}
+
+	header := fmt.Sprintf("chunked-blob %d\x00", totalSize)
+	return append([]byte(header), body.Bytes()...)
+}
+
+// parseChunkedBlobObject parses the body of a decompressed
+// `chunked-blob <size>\x00...` object (header already stripped) back into
+// its chunk entries.
+func parseChunkedBlobObject(body []byte) ([]chunkEntry, error) {
+	const entrySize = 8 + 8 + 20
+
+	if len(body)%entrySize != 0 {
+		return nil, fmt.Errorf("invalid chunked-blob object (body len %d not a multiple of %d)", len(body), entrySize)
+	}
+
+	entries := make([]chunkEntry, 0, len(body)/entrySize)
+	for len(body) > 0 {
+		entry := chunkEntry{
+			Offset: int64(binary.BigEndian.Uint64(body[0:8])),
+			Size:   int64(binary.BigEndian.Uint64(body[8:16])),
+		}
+		copy(entry.Hash[:], body[16:36])
+		entries = append(entries, entry)
+		body = body[entrySize:]
+	}
+
+	return entries, nil
+}
+
+// createChunkedBlobObject splits the file at filePath into content-defined
+// chunks, writes each chunk as its own blob object, and writes a
+// chunked-blob object listing them. It returns the chunked-blob's Object,
+// ready to be inserted into a tree like a regular blob.
+func createChunkedBlobObject(filePath string, size int64) (*Object, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	chunks, err := splitChunks(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]chunkEntry, 0, len(chunks))
+	var offset int64
+	for _, chunk := range chunks {
+		hash, err := writeBlobObject(chunk)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, chunkEntry{Offset: offset, Size: int64(len(chunk)), Hash: hash})
+		offset += int64(len(chunk))
+	}
+
+	contents := serializeChunkedBlobObject(size, entries)
+	hash := sha1.Sum(contents)
+
+	object := &Object{
+		ShaHash:  hash[:],
+		Mode:     ObjectModeFile,
+		FileName: filepath.Base(filePath),
+	}
+
+	if err := object.writeToDisk(contents); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// hashFile computes the hash mygit would store for filePath without
+// writing any objects to disk, consulting cache first and honoring the
+// same chunking threshold createObjectFromFile uses. status and diff use
+// this to compare the working tree against a tree or index without
+// mutating .git/objects. filePath is Lstat'd, so a symlink hashes its own
+// target string rather than the file it points at.
+func hashFile(filePath string, cache CacheContext, chunkThreshold int64) (string, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	key := CacheKey{
+		Path:    absPath,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Mode:    uint32(info.Mode()),
+	}
+
+	if hash, ok := cache.Get(key); ok {
+		return hash, nil
+	}
+
+	var hash [20]byte
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return "", err
+		}
+		hash = blobHash([]byte(target))
+	case info.Size() >= chunkThreshold:
+		hash, err = computeChunkedBlobHash(filePath, info.Size())
+		if err != nil {
+			return "", err
+		}
+	default:
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		hash = blobHash(contents)
+	}
+
+	hashStr := fmt.Sprintf("%x", hash)
+	cache.Set(key, hashStr)
+	return hashStr, nil
+}
+
+// computeChunkedBlobHash computes the hash createChunkedBlobObject would
+// produce for filePath, without writing any chunk or chunked-blob objects
+// to disk.
+func computeChunkedBlobHash(filePath string, size int64) ([20]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return [20]byte{}, err
+	}
+	defer file.Close()
+
+	chunks, err := splitChunks(file)
+	if err != nil {
+		return [20]byte{}, err
+	}
+
+	entries := make([]chunkEntry, 0, len(chunks))
+	var offset int64
+	for _, chunk := range chunks {
+		entries = append(entries, chunkEntry{Offset: offset, Size: int64(len(chunk)), Hash: blobHash(chunk)})
+		offset += int64(len(chunk))
+	}
+
+	contents := serializeChunkedBlobObject(size, entries)
+	return sha1.Sum(contents), nil
+}
+
+// reassembleChunkedBlob reads every chunk listed in a chunked-blob object's
+// body, in order, and concatenates their contents back into the original
+// file bytes.
+func reassembleChunkedBlob(body []byte) ([]byte, error) {
+	entries, err := parseChunkedBlobObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	for _, entry := range entries {
+		kind, data, err := readObject(fmt.Sprintf("%x", entry.Hash))
+		if err != nil {
+			return nil, err
+		}
+		if kind != "blob" {
+			return nil, fmt.Errorf("chunk %x is a %s, not a blob", entry.Hash, kind)
+		}
+		if int64(len(data)) != entry.Size {
+			return nil, fmt.Errorf("chunk %x has size %d, expected %d", entry.Hash, len(data), entry.Size)
+		}
+		out.Write(data)
+	}
+
+	return out.Bytes(), nil
+}
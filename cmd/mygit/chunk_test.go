@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestChunkRoundTrip splits a file large enough to produce several chunks,
+// writes the chunked-blob object it describes, and checks that reading it
+// back and reassembling the chunks reproduces the original bytes.
+func TestChunkRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+
+	if err := initRepo(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 6*chunkMinSize)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	filePath := filepath.Join(dir, "big-file")
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := createChunkedBlobObject(filePath, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kind, body, err := readObject(fmt.Sprintf("%x", object.ShaHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != "chunked-blob" {
+		t.Fatalf("got kind %q, want chunked-blob", kind)
+	}
+
+	entries, err := parseChunkedBlobObject(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("expected the chunker to split %d bytes into multiple chunks, got %d", len(data), len(entries))
+	}
+
+	reassembled, err := reassembleChunkedBlob(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reassembled, data) {
+		t.Fatal("reassembled blob does not match original file contents")
+	}
+}
@@ -1,21 +1,44 @@
 package main
 
 import (
-	"bytes"
-	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 )
 
-func writeTree() error {
-	t, err := createTreeFromDir(".")
+func writeTree(args []string) error {
+	var cache CacheContext = noopCache{}
+	var persistent *fileCache
+
+	if !slices.Contains(args, "--no-cache") {
+		fc, err := loadFileCache()
+		if err != nil {
+			return err
+		}
+		cache = fc
+		persistent = fc
+	}
+
+	chunkThreshold, err := parseChunkThresholdFlag(args)
 	if err != nil {
 		return err
 	}
 
+	t, err := createTreeFromDir(".", cache, chunkThreshold)
+	if err != nil {
+		return err
+	}
+
+	if persistent != nil {
+		if err := persistent.Save(); err != nil {
+			return err
+		}
+	}
+
 	if t != nil {
 		fmt.Println(fmt.Sprintf("%x", t.ShaHash))
 	}
@@ -83,27 +106,111 @@ func lsTree(args []string) error {
 	return nil
 }
 
-func createObjectFromFile(filePath string) (*Object, error) {
+// createObjectFromFile hashes filePath as a blob object and writes it to
+// disk, consulting cache first so unchanged files don't need to be
+// re-read and re-hashed. Files at or above chunkThreshold bytes are split
+// into content-defined chunks and stored as a chunked-blob object instead
+// of a single blob. filePath is Lstat'd, not Stat'd, so a symlink is
+// stored as its own target string under ObjectModeSymlink rather than
+// dereferenced, and the executable bit is preserved as ObjectModeExe.
+func createObjectFromFile(filePath string, cache CacheContext, chunkThreshold int64) (*Object, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := objectModeFromFileInfo(info)
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	key := CacheKey{
+		Path:    absPath,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Mode:    uint32(info.Mode()),
+	}
+
+	if hash, ok := cache.Get(key); ok {
+		shaHash, err := hex.DecodeString(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Object{
+			ShaHash:  shaHash,
+			Mode:     mode,
+			FileName: filepath.Base(filePath),
+		}, nil
+	}
+
+	if mode == ObjectModeSymlink {
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := writeBlobObject([]byte(target))
+		if err != nil {
+			return nil, err
+		}
+
+		object := &Object{ShaHash: hash[:], Mode: mode, FileName: filepath.Base(filePath)}
+		cache.Set(key, fmt.Sprintf("%x", hash))
+		return object, nil
+	}
+
+	if info.Size() >= chunkThreshold {
+		object, err := createChunkedBlobObject(filePath, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		object.Mode = mode
+
+		cache.Set(key, fmt.Sprintf("%x", object.ShaHash))
+		return object, nil
+	}
+
 	contents, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	blobSize := fmt.Sprintf("blob %d", len(contents))
-	objectBytes := bytes.Join([][]byte{[]byte(blobSize), contents}, []byte{0})
-	hash := sha1.Sum(objectBytes)
+	hash, err := writeBlobObject(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &Object{ShaHash: hash[:], Mode: mode, FileName: filepath.Base(filePath)}
 
-	object := &Object{
-		ShaHash:  hash[:],
-		Mode:     ObjectModeFile,
-		FileName: filepath.Base(filePath),
+	cache.Set(key, fmt.Sprintf("%x", hash))
+	return object, nil
+}
+
+// parseChunkThresholdFlag extracts an optional `--chunk-threshold <bytes>`
+// pair from args, returning defaultChunkThreshold if it's absent.
+func parseChunkThresholdFlag(args []string) (int64, error) {
+	for i, arg := range args {
+		if arg != "--chunk-threshold" {
+			continue
+		}
+		if i+1 >= len(args) {
+			return 0, fmt.Errorf("--chunk-threshold requires a byte count")
+		}
+		threshold, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --chunk-threshold %q: %w", args[i+1], err)
+		}
+		return threshold, nil
 	}
 
-	return object, object.writeToDisk(objectBytes)
+	return defaultChunkThreshold, nil
 }
 
 func hashObject(args []string) error {
-	if len(args) != 2 {
+	if len(args) < 2 {
 		return fmt.Errorf("Expected 2 arguments")
 	}
 
@@ -112,7 +219,12 @@ func hashObject(args []string) error {
 	}
 
 	filePath := args[1]
-	object, err := createObjectFromFile(filePath)
+	chunkThreshold, err := parseChunkThresholdFlag(args[2:])
+	if err != nil {
+		return err
+	}
+
+	object, err := createObjectFromFile(filePath, noopCache{}, chunkThreshold)
 	if err != nil {
 		return err
 	}
@@ -150,15 +262,33 @@ func catFile(args []string) error {
 		return err
 	}
 
-	delimIndex := bytes.IndexByte(decompressedBytes, 0)
-	if delimIndex == -1 {
+	header, payloadBytes := splitOn(decompressedBytes, 0)
+	if len(header) == 0 {
 		return fmt.Errorf("Invalid object")
 	}
 
-	payloadBytes := decompressedBytes[delimIndex+1:]
-	payload := string(payloadBytes)
+	kind, _ := splitOn(header, ' ')
+	switch string(kind) {
+	case "commit":
+		c, err := parseCommitObject(payloadBytes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(formatCommitObject(c))
+		return nil
+
+	case "chunked-blob":
+		contents, err := reassembleChunkedBlob(payloadBytes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(contents))
+		return nil
+	}
 
-	fmt.Print(payload)
+	fmt.Print(string(payloadBytes))
 	return nil
 }
 
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommitObject holds the parsed contents of a `commit` object:
+// the tree it snapshots, its parent commits, and author/committer metadata.
+type CommitObject struct {
+	Tree          string
+	Parents       []string
+	Author        string
+	AuthorTime    time.Time
+	Committer     string
+	CommitterTime time.Time
+	Message       string
+}
+
+// identity reads `GIT_<WHO>_NAME`/`GIT_<WHO>_EMAIL` from the environment,
+// falling back to a generic mygit identity when unset.
+func identity(who string) string {
+	name := os.Getenv("GIT_" + who + "_NAME")
+	if name == "" {
+		name = "mygit"
+	}
+
+	email := os.Getenv("GIT_" + who + "_EMAIL")
+	if email == "" {
+		email = "mygit@localhost"
+	}
+
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+func formatSignature(identity string, t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	return fmt.Sprintf(
+		"%s %d %s%02d%02d",
+		identity,
+		t.Unix(),
+		sign,
+		offset/3600,
+		(offset%3600)/60,
+	)
+}
+
+// serializeCommitObject renders a commit the way `git commit-tree` would:
+//
+//	tree <hash>
+//	parent <hash>
+//	...
+//	author <name> <email> <unix-ts> <tz>
+//	committer <name> <email> <unix-ts> <tz>
+//
+//	<message>
+func serializeCommitObject(c *CommitObject) []byte {
+	var body bytes.Buffer
+
+	fmt.Fprintf(&body, "tree %s\n", c.Tree)
+	for _, parent := range c.Parents {
+		fmt.Fprintf(&body, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&body, "author %s\n", formatSignature(c.Author, c.AuthorTime))
+	fmt.Fprintf(&body, "committer %s\n", formatSignature(c.Committer, c.CommitterTime))
+	body.WriteByte('\n')
+	body.WriteString(c.Message)
+
+	header := fmt.Sprintf("commit %d\x00", body.Len())
+	return append([]byte(header), body.Bytes()...)
+}
+
+// parseCommitObject parses the body of a decompressed `commit <len>\x00...`
+// object (header already stripped) into its fields.
+func parseCommitObject(body []byte) (*CommitObject, error) {
+	c := &CommitObject{}
+
+	lines := bytes.Split(body, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			c.Message = string(bytes.Join(lines[i+1:], []byte("\n")))
+			return c, nil
+		}
+
+		key, rest := splitOn(line, ' ')
+		switch string(key) {
+		case "tree":
+			c.Tree = string(rest)
+		case "parent":
+			c.Parents = append(c.Parents, string(rest))
+		case "author":
+			identity, t, err := parseSignature(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid author line: %w", err)
+			}
+			c.Author, c.AuthorTime = identity, t
+		case "committer":
+			identity, t, err := parseSignature(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid committer line: %w", err)
+			}
+			c.Committer, c.CommitterTime = identity, t
+		default:
+			return nil, fmt.Errorf("unrecognized commit header %q", key)
+		}
+	}
+
+	return nil, fmt.Errorf("commit object missing message separator")
+}
+
+// parseSignature splits `<name> <email> <unix-ts> <tz>` into the identity
+// portion and the timestamp it encodes.
+func parseSignature(line []byte) (string, time.Time, error) {
+	fields := strings.Fields(string(line))
+	if len(fields) < 4 {
+		return "", time.Time{}, fmt.Errorf("expected name, email, timestamp and tz")
+	}
+
+	tzField := fields[len(fields)-1]
+	tsField := fields[len(fields)-2]
+	identity := strings.Join(fields[:len(fields)-2], " ")
+
+	unixTime, err := strconv.ParseInt(tsField, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid timestamp %q: %w", tsField, err)
+	}
+
+	loc, err := parseTimezone(tzField)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return identity, time.Unix(unixTime, 0).In(loc), nil
+}
+
+func parseTimezone(tz string) (*time.Location, error) {
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return nil, fmt.Errorf("invalid timezone %q", tz)
+	}
+
+	hours, err := strconv.Atoi(tz[1:3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	minutes, err := strconv.Atoi(tz[3:5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	offset := hours*3600 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+
+	return time.FixedZone(tz, offset), nil
+}
+
+// buildCommitObject parses `commit-tree`-style arguments (`<tree> [-p
+// <parent>]... -m <msg>`), writes the resulting commit object to disk, and
+// returns it.
+func buildCommitObject(args []string) (*Object, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("usage: mygit commit-tree <tree-sha> [-p <parent-sha>]... -m <message>")
+	}
+
+	c := &CommitObject{
+		Tree:          args[0],
+		Author:        identity("AUTHOR"),
+		AuthorTime:    time.Now(),
+		Committer:     identity("COMMITTER"),
+		CommitterTime: time.Now(),
+	}
+
+	var messages []string
+	rest := args[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case "-p":
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("-p requires a parent commit sha")
+			}
+			c.Parents = append(c.Parents, rest[1])
+			rest = rest[2:]
+		case "-m":
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("-m requires a message")
+			}
+			messages = append(messages, rest[1])
+			rest = rest[2:]
+		default:
+			return nil, fmt.Errorf("unrecognized argument %q", rest[0])
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("commit message required (-m)")
+	}
+	c.Message = strings.Join(messages, "\n\n")
+
+	contents := serializeCommitObject(c)
+	hash := sha1.Sum(contents)
+
+	object := &Object{
+		Kind:    ObjKindCommit,
+		Commit:  c,
+		ShaHash: hash[:],
+	}
+
+	if err := object.writeToDisk(contents); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// formatCommitObject renders a commit the way `cat-file -p` prints one.
+func formatCommitObject(c *CommitObject) string {
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "tree %s\n", c.Tree)
+	for _, parent := range c.Parents {
+		fmt.Fprintf(&out, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&out, "author %s\n", formatSignature(c.Author, c.AuthorTime))
+	fmt.Fprintf(&out, "committer %s\n", formatSignature(c.Committer, c.CommitterTime))
+	out.WriteByte('\n')
+	out.WriteString(c.Message)
+
+	return out.String()
+}
+
+func commitTree(args []string) error {
+	object, err := buildCommitObject(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(fmt.Sprintf("%x", object.ShaHash))
+	return nil
+}
+
+// currentBranchRef resolves the ref that HEAD points to, e.g. "refs/heads/main".
+func currentBranchRef() (string, error) {
+	headBytes, err := os.ReadFile(".git/HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	head := strings.TrimSpace(string(headBytes))
+	const prefix = "ref: "
+	if !strings.HasPrefix(head, prefix) {
+		return "", fmt.Errorf("detached HEAD is not supported")
+	}
+
+	return strings.TrimPrefix(head, prefix), nil
+}
+
+// readRef reads the commit sha a ref points to, returning "" if the ref
+// doesn't exist yet (e.g. the very first commit).
+func readRef(ref string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(".git", ref))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func updateRef(ref string, hash string) error {
+	path := filepath.Join(".git", ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(hash+"\n"), 0644)
+}
+
+// commit snapshots the working directory into a tree, commits it on top of
+// the current branch's HEAD, and advances the branch to the new commit.
+func commit(args []string) error {
+	var messages []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-m" && i+1 < len(args) {
+			messages = append(messages, args[i+1])
+			i++
+		}
+	}
+
+	if len(messages) == 0 {
+		return fmt.Errorf("usage: mygit commit -m <message>")
+	}
+
+	cache, err := loadFileCache()
+	if err != nil {
+		return err
+	}
+
+	tree, err := createTreeFromDir(".", cache, defaultChunkThreshold)
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		return fmt.Errorf("nothing to commit, working tree is empty")
+	}
+
+	if err := cache.Save(); err != nil {
+		return err
+	}
+
+	branchRef, err := currentBranchRef()
+	if err != nil {
+		return err
+	}
+
+	parentHash, err := readRef(branchRef)
+	if err != nil {
+		return err
+	}
+
+	commitTreeArgs := []string{fmt.Sprintf("%x", tree.ShaHash)}
+	if parentHash != "" {
+		commitTreeArgs = append(commitTreeArgs, "-p", parentHash)
+	}
+	for _, message := range messages {
+		commitTreeArgs = append(commitTreeArgs, "-m", message)
+	}
+
+	commitObject, err := buildCommitObject(commitTreeArgs)
+	if err != nil {
+		return err
+	}
+
+	commitHash := fmt.Sprintf("%x", commitObject.ShaHash)
+	if err := updateRef(branchRef, commitHash); err != nil {
+		return err
+	}
+
+	if err := writeIndexFromTree(fmt.Sprintf("%x", tree.ShaHash)); err != nil {
+		return err
+	}
+
+	fmt.Println(commitHash)
+	return nil
+}
+
+// writeIndexFromTree overwrites .git/index with the flattened contents of
+// the tree at hash, so status has a staging layer that matches what was
+// just committed.
+func writeIndexFromTree(hash string) error {
+	files, err := flattenTree(hash, "")
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]IndexEntry, len(files))
+	for _, f := range files {
+		entries[f.Path] = IndexEntry{Hash: f.Hash, Mode: f.Mode}
+	}
+
+	return writeIndex(entries)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each hunk of
+// changes, matching git's default.
+const diffContext = 3
+
+// diffOpKind distinguishes the three line-level edits a Myers diff can
+// produce.
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp is one line of an edit script: Line is its text, Kind says
+// whether it's shared between both files, only in the old one, or only in
+// the new one.
+type diffOp struct {
+	Kind diffOpKind
+	Line string
+}
+
+// splitLines splits blob contents into lines for a line-based diff,
+// dropping the trailing empty element a trailing newline would otherwise
+// add.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// myersDiff computes the shortest edit script turning a into b, using the
+// classic Myers O(ND) algorithm: it searches increasing edit distances d,
+// recording at each one the furthest-reaching point reachable on every
+// diagonal k = x-y, then backtracks through that history to recover the
+// script.
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max
+
+	v := make([]int, 2*max+1)
+	var trace [][]int
+	finalD := -1
+
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				finalD = d
+				break search
+			}
+		}
+	}
+
+	x, y := n, m
+	var ops []diffOp
+	for d := finalD; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{Kind: diffEqual, Line: a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			ops = append(ops, diffOp{Kind: diffInsert, Line: b[prevY]})
+		} else {
+			ops = append(ops, diffOp{Kind: diffDelete, Line: a[prevX]})
+		}
+
+		x, y = prevX, prevY
+	}
+	for x > 0 {
+		ops = append(ops, diffOp{Kind: diffEqual, Line: a[x-1]})
+		x--
+	}
+
+	slices.Reverse(ops)
+	return ops
+}
+
+// changeRuns returns the [start, end) index ranges of every maximal run of
+// non-equal ops.
+func changeRuns(ops []diffOp) [][2]int {
+	var runs [][2]int
+
+	i := 0
+	for i < len(ops) {
+		if ops[i].Kind == diffEqual {
+			i++
+			continue
+		}
+		start := i
+		for i < len(ops) && ops[i].Kind != diffEqual {
+			i++
+		}
+		runs = append(runs, [2]int{start, i})
+	}
+
+	return runs
+}
+
+// hunkRanges expands every change run by `context` lines of surrounding
+// equal context and merges any ranges that now overlap, producing the
+// [start, end) index ranges a unified diff renders as separate hunks.
+func hunkRanges(ops []diffOp, context int) [][2]int {
+	runs := changeRuns(ops)
+	if len(runs) == 0 {
+		return nil
+	}
+
+	var ranges [][2]int
+	for _, run := range runs {
+		start := max(run[0]-context, 0)
+		end := min(run[1]+context, len(ops))
+
+		if len(ranges) > 0 && start <= ranges[len(ranges)-1][1] {
+			ranges[len(ranges)-1][1] = end
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	return ranges
+}
+
+// unifiedDiff renders a git-style unified patch between oldContent and
+// newContent. Identical content produces an empty string.
+func unifiedDiff(oldPath, newPath string, oldContent, newContent []byte) string {
+	oldLines := splitLines(oldContent)
+	newLines := splitLines(newContent)
+	ops := myersDiff(oldLines, newLines)
+
+	ranges := hunkRanges(ops, diffContext)
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	oldCount := make([]int, len(ops)+1)
+	newCount := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldCount[i+1], newCount[i+1] = oldCount[i], newCount[i]
+		switch op.Kind {
+		case diffEqual:
+			oldCount[i+1]++
+			newCount[i+1]++
+		case diffDelete:
+			oldCount[i+1]++
+		case diffInsert:
+			newCount[i+1]++
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- a/%s\n", oldPath)
+	fmt.Fprintf(&buf, "+++ b/%s\n", newPath)
+
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		oldLen := oldCount[end] - oldCount[start]
+		newLen := newCount[end] - newCount[start]
+
+		oldStart := oldCount[start]
+		if oldLen > 0 {
+			oldStart++
+		}
+		newStart := newCount[start]
+		if newLen > 0 {
+			newStart++
+		}
+
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range ops[start:end] {
+			switch op.Kind {
+			case diffEqual:
+				fmt.Fprintf(&buf, " %s\n", op.Line)
+			case diffDelete:
+				fmt.Fprintf(&buf, "-%s\n", op.Line)
+			case diffInsert:
+				fmt.Fprintf(&buf, "+%s\n", op.Line)
+			}
+		}
+	}
+
+	return buf.String()
+}
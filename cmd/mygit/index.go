@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const indexFilePath = ".git/index"
+
+// IndexEntry records the hash and mode last staged for a path. For now
+// the index is written wholesale by `commit` from the tree it just wrote,
+// so it doubles as a snapshot of HEAD; a real staging area (an `add`
+// command) can build on top of this file format later.
+type IndexEntry struct {
+	Hash string
+	Mode ObjectMode
+}
+
+// readIndex loads .git/index, or returns an empty index if it doesn't
+// exist yet (e.g. before the first commit).
+func readIndex() (map[string]IndexEntry, error) {
+	data, err := os.ReadFile(indexFilePath)
+	if os.IsNotExist(err) {
+		return map[string]IndexEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]IndexEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// writeIndex overwrites .git/index with entries.
+func writeIndex(entries map[string]IndexEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(indexFilePath, data, 0644)
+}
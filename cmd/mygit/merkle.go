@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileState is one path's hash and mode as seen in a single source: the
+// HEAD tree, the index, or the working directory.
+type FileState struct {
+	Path string
+	Hash string
+	Mode ObjectMode
+}
+
+// flattenTree recursively walks the tree at hash, expanding subtrees with
+// parseTreeObject, and returns every blob it contains as a flat,
+// slash-joined FileState list. prefix is the path already descended into.
+func flattenTree(hash, prefix string) ([]FileState, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	treeFile, err := os.Open(filePathFromObjectHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	defer treeFile.Close()
+
+	contents, err := decompress(treeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := parseTreeObject(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []FileState
+	for _, entry := range entries {
+		path := entry.FileName
+		if prefix != "" {
+			path = prefix + "/" + entry.FileName
+		}
+		entryHash := fmt.Sprintf("%x", entry.ShaHash)
+
+		if entry.Mode == ObjectModeDir {
+			sub, err := flattenTree(entryHash, path)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sub...)
+			continue
+		}
+
+		out = append(out, FileState{Path: path, Hash: entryHash, Mode: entry.Mode})
+	}
+
+	return out, nil
+}
+
+// flattenIndex converts the on-disk index into the same flat FileState
+// shape flattenTree and flattenWorkingDir produce.
+func flattenIndex(index map[string]IndexEntry) []FileState {
+	out := make([]FileState, 0, len(index))
+	for path, entry := range index {
+		out = append(out, FileState{Path: path, Hash: entry.Hash, Mode: entry.Mode})
+	}
+	return out
+}
+
+// flattenWorkingDir walks the working directory (skipping .git) and hashes
+// every file it finds, without writing any objects to disk.
+func flattenWorkingDir(cache CacheContext, chunkThreshold int64) ([]FileState, error) {
+	var out []FileState
+
+	err := filepath.Walk(".", func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		if f.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if f.IsDir() {
+			return nil
+		}
+
+		hash, err := hashFile(path, cache, chunkThreshold)
+		if err != nil {
+			return err
+		}
+
+		out = append(out, FileState{Path: filepath.ToSlash(path), Hash: hash, Mode: objectModeFromFileInfo(f)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// mergeEntry is one path's state in up to three sources, as produced by a
+// single sorted merge-walk over them. A nil pointer means the path is
+// absent from that source.
+type mergeEntry struct {
+	Path  string
+	Head  *FileState
+	Index *FileState
+	Work  *FileState
+}
+
+// walkMerkleTrie merges three sorted-by-path FileState slices (the merkletrie
+// approach go-git's worktree uses) into a single pass over every distinct
+// path, yielding each path's state in all three sources at once.
+func walkMerkleTrie(head, index, work []FileState) []mergeEntry {
+	byPath := func(a, b FileState) int { return strings.Compare(a.Path, b.Path) }
+	slices.SortFunc(head, byPath)
+	slices.SortFunc(index, byPath)
+	slices.SortFunc(work, byPath)
+
+	var entries []mergeEntry
+	i, j, k := 0, 0, 0
+
+	for i < len(head) || j < len(index) || k < len(work) {
+		path := ""
+		if i < len(head) && (path == "" || head[i].Path < path) {
+			path = head[i].Path
+		}
+		if j < len(index) && (path == "" || index[j].Path < path) {
+			path = index[j].Path
+		}
+		if k < len(work) && (path == "" || work[k].Path < path) {
+			path = work[k].Path
+		}
+
+		entry := mergeEntry{Path: path}
+		if i < len(head) && head[i].Path == path {
+			entry.Head = &head[i]
+			i++
+		}
+		if j < len(index) && index[j].Path == path {
+			entry.Index = &index[j]
+			j++
+		}
+		if k < len(work) && work[k].Path == path {
+			entry.Work = &work[k]
+			k++
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// changeKind classifies the transition from one FileState to another,
+// either of which may be absent.
+type changeKind int
+
+const (
+	unmodified changeKind = iota
+	added
+	modified
+	deleted
+)
+
+func (k changeKind) String() string {
+	switch k {
+	case added:
+		return "added"
+	case modified:
+		return "modified"
+	case deleted:
+		return "deleted"
+	default:
+		return "unmodified"
+	}
+}
+
+// classify compares from -> to and reports what kind of change that is.
+func classify(from, to *FileState) changeKind {
+	switch {
+	case from == nil && to == nil:
+		return unmodified
+	case from == nil:
+		return added
+	case to == nil:
+		return deleted
+	case from.Hash != to.Hash || from.Mode != to.Mode:
+		return modified
+	default:
+		return unmodified
+	}
+}
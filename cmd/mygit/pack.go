@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+type packObjectType int
+
+const (
+	packObjCommit   packObjectType = 1
+	packObjTree     packObjectType = 2
+	packObjBlob     packObjectType = 3
+	packObjTag      packObjectType = 4
+	packObjOfsDelta packObjectType = 6
+	packObjRefDelta packObjectType = 7
+)
+
+func (k packObjectType) String() string {
+	switch k {
+	case packObjCommit:
+		return "commit"
+	case packObjTree:
+		return "tree"
+	case packObjBlob:
+		return "blob"
+	case packObjTag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+// packObject is a fully resolved (non-delta) object decoded from a packfile.
+type packObject struct {
+	Kind packObjectType
+	Data []byte
+}
+
+// countingReader wraps a reader and tracks how many bytes have been read
+// through it, which readPackfile needs to compute OFS_DELTA base offsets.
+// It implements io.ByteReader so compress/zlib reads through it directly
+// instead of wrapping it in its own buffer, which would read past the end
+// of the zlib stream and throw off the byte count.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// readPackfile parses a "PACK"-prefixed packfile stream: the header, the
+// object count, and every object entry, resolving OFS_DELTA/REF_DELTA
+// entries against objects already seen earlier in the stream (or already
+// on disk, for thin packs). Every resolved object is written to
+// .git/objects. It returns the hash of every object written, in stream order.
+func readPackfile(r io.Reader) ([]string, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+
+	var magic [4]byte
+	if _, err := io.ReadFull(cr, magic[:]); err != nil {
+		return nil, err
+	}
+	if string(magic[:]) != "PACK" {
+		return nil, fmt.Errorf("not a packfile (bad magic %q)", magic)
+	}
+
+	var version, count uint32
+	if err := binary.Read(cr, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(cr, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	if version != 2 {
+		return nil, fmt.Errorf("unsupported packfile version %d", version)
+	}
+
+	byOffset := make(map[int64]*packObject, count)
+	byHash := make(map[string]*packObject, count)
+	hashes := make([]string, 0, count)
+
+	for i := uint32(0); i < count; i++ {
+		objOffset := cr.n
+
+		obj, err := readPackObject(cr, objOffset, byOffset, byHash)
+		if err != nil {
+			return nil, fmt.Errorf("reading pack object %d/%d: %w", i+1, count, err)
+		}
+
+		hash, err := writePackObject(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		byOffset[objOffset] = obj
+		byHash[hash] = obj
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, nil
+}
+
+// readPackObject decodes a single entry at the current stream position:
+// the type+size header, then either a zlib-compressed payload (for
+// commit/tree/blob/tag) or a delta instruction stream resolved against an
+// already-seen base object (for ofs-delta/ref-delta).
+func readPackObject(
+	cr *countingReader,
+	objOffset int64,
+	byOffset map[int64]*packObject,
+	byHash map[string]*packObject,
+) (*packObject, error) {
+	kind, _, err := readPackObjectHeader(cr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case packObjCommit, packObjTree, packObjBlob, packObjTag:
+		data, err := zlibDecompressFrom(cr)
+		if err != nil {
+			return nil, err
+		}
+		return &packObject{Kind: kind, Data: data}, nil
+
+	case packObjOfsDelta:
+		negOffset, err := readOfsDeltaOffset(cr)
+		if err != nil {
+			return nil, err
+		}
+
+		delta, err := zlibDecompressFrom(cr)
+		if err != nil {
+			return nil, err
+		}
+
+		base, ok := byOffset[objOffset-negOffset]
+		if !ok {
+			return nil, fmt.Errorf("ofs-delta base at offset %d not found", objOffset-negOffset)
+		}
+
+		data, err := applyDelta(base.Data, delta)
+		if err != nil {
+			return nil, err
+		}
+		return &packObject{Kind: base.Kind, Data: data}, nil
+
+	case packObjRefDelta:
+		var baseHash [20]byte
+		if _, err := io.ReadFull(cr, baseHash[:]); err != nil {
+			return nil, err
+		}
+
+		delta, err := zlibDecompressFrom(cr)
+		if err != nil {
+			return nil, err
+		}
+
+		base, err := resolveDeltaBase(fmt.Sprintf("%x", baseHash), byHash)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := applyDelta(base.Data, delta)
+		if err != nil {
+			return nil, err
+		}
+		return &packObject{Kind: base.Kind, Data: data}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported pack object type %d", kind)
+	}
+}
+
+// readPackObjectHeader decodes the variable-length type+size byte sequence
+// that precedes every pack entry: a 3-bit type in bits 4-6 of the first
+// byte, and a size built from the low 4 bits of the first byte plus 7 bits
+// from every continuation byte (MSB=1 means "more bytes follow").
+func readPackObjectHeader(cr *countingReader) (packObjectType, int64, error) {
+	b, err := cr.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	kind := packObjectType((b >> 4) & 0x7)
+	size := int64(b & 0x0f)
+	shift := uint(4)
+
+	for b&0x80 != 0 {
+		b, err = cr.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		size |= int64(b&0x7f) << shift
+		shift += 7
+	}
+
+	return kind, size, nil
+}
+
+// readOfsDeltaOffset decodes the negative, big-endian, 7-bit-per-byte
+// offset (with a +1 bias on every continuation byte) used by OFS_DELTA
+// entries to locate their base relative to the delta's own offset.
+func readOfsDeltaOffset(cr *countingReader) (int64, error) {
+	b, err := cr.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	offset := int64(b & 0x7f)
+	for b&0x80 != 0 {
+		b, err = cr.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		offset++
+		offset = (offset << 7) | int64(b&0x7f)
+	}
+
+	return offset, nil
+}
+
+func zlibDecompressFrom(cr *countingReader) ([]byte, error) {
+	zr, err := zlib.NewReader(cr)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
+
+// resolveDeltaBase looks up a ref-delta base among objects already decoded
+// from this pack, falling back to .git/objects for thin packs whose base
+// is assumed to already exist locally.
+func resolveDeltaBase(hash string, byHash map[string]*packObject) (*packObject, error) {
+	if obj, ok := byHash[hash]; ok {
+		return obj, nil
+	}
+
+	kind, body, err := readObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("ref-delta base %s not found: %w", hash, err)
+	}
+
+	switch kind {
+	case "commit":
+		return &packObject{Kind: packObjCommit, Data: body}, nil
+	case "tree":
+		return &packObject{Kind: packObjTree, Data: body}, nil
+	case "blob":
+		return &packObject{Kind: packObjBlob, Data: body}, nil
+	case "tag":
+		return &packObject{Kind: packObjTag, Data: body}, nil
+	default:
+		return nil, fmt.Errorf("ref-delta base %s has unexpected kind %q", hash, kind)
+	}
+}
+
+// applyDelta reconstructs a target object from a base object and a Git
+// delta instruction stream: a varint source size, a varint target size,
+// then a sequence of COPY (MSB=1, offset/size taken from the low 7 bits)
+// and INSERT (MSB=0, the next N bytes are literal) instructions.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+
+	sourceSize, err := readDeltaVarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != uint64(len(base)) {
+		return nil, fmt.Errorf("delta source size %d does not match base size %d", sourceSize, len(base))
+	}
+
+	targetSize, err := readDeltaVarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Grow(int(targetSize))
+
+	for {
+		opByte, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if opByte&0x80 != 0 {
+			offset, size, err := readCopyInstruction(r, opByte)
+			if err != nil {
+				return nil, err
+			}
+			if int64(offset)+int64(size) > int64(len(base)) {
+				return nil, fmt.Errorf("delta copy instruction out of range")
+			}
+			out.Write(base[offset : offset+size])
+		} else if opByte != 0 {
+			literal := make([]byte, opByte)
+			if _, err := io.ReadFull(r, literal); err != nil {
+				return nil, err
+			}
+			out.Write(literal)
+		} else {
+			return nil, fmt.Errorf("invalid delta instruction byte 0")
+		}
+	}
+
+	if out.Len() != int(targetSize) {
+		return nil, fmt.Errorf("delta produced %d bytes, expected %d", out.Len(), targetSize)
+	}
+
+	return out.Bytes(), nil
+}
+
+// readCopyInstruction decodes the offset/size fields of a COPY instruction,
+// whose presence is selected by the low 7 bits of the instruction byte.
+func readCopyInstruction(r *bytes.Reader, opByte byte) (offset, size uint32, err error) {
+	readField := func(present bool, shift uint) (uint32, error) {
+		if !present {
+			return 0, nil
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		return uint32(b) << shift, nil
+	}
+
+	var v uint32
+	if v, err = readField(opByte&0x01 != 0, 0); err != nil {
+		return 0, 0, err
+	}
+	offset |= v
+	if v, err = readField(opByte&0x02 != 0, 8); err != nil {
+		return 0, 0, err
+	}
+	offset |= v
+	if v, err = readField(opByte&0x04 != 0, 16); err != nil {
+		return 0, 0, err
+	}
+	offset |= v
+	if v, err = readField(opByte&0x08 != 0, 24); err != nil {
+		return 0, 0, err
+	}
+	offset |= v
+
+	if v, err = readField(opByte&0x10 != 0, 0); err != nil {
+		return 0, 0, err
+	}
+	size |= v
+	if v, err = readField(opByte&0x20 != 0, 8); err != nil {
+		return 0, 0, err
+	}
+	size |= v
+	if v, err = readField(opByte&0x40 != 0, 16); err != nil {
+		return 0, 0, err
+	}
+	size |= v
+
+	if size == 0 {
+		size = 0x10000
+	}
+
+	return offset, size, nil
+}
+
+func readDeltaVarint(r *bytes.Reader) (uint64, error) {
+	var value uint64
+	var shift uint
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return value, nil
+}
+
+// writePackObject hashes a resolved pack object like hash-object would
+// and writes it to .git/objects.
+func writePackObject(obj *packObject) (string, error) {
+	header := fmt.Sprintf("%s %d\x00", obj.Kind, len(obj.Data))
+	contents := append([]byte(header), obj.Data...)
+	hash := sha1.Sum(contents)
+
+	object := &Object{ShaHash: hash[:]}
+	if err := object.writeToDisk(contents); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash), nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// clone fetches a repository over the Git smart HTTP protocol and checks
+// out its default branch into dir.
+func clone(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: mygit clone <url> <dir>")
+	}
+
+	url, dir := args[0], args[1]
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	if err := initRepo(); err != nil {
+		return err
+	}
+
+	refs, err := discoverRefs(url)
+	if err != nil {
+		return err
+	}
+
+	head, ok := refs["HEAD"]
+	if !ok {
+		return fmt.Errorf("remote repository advertised no HEAD ref")
+	}
+
+	packBytes, err := fetchPack(url, wantedHashes(refs))
+	if err != nil {
+		return err
+	}
+
+	if _, err := readPackfile(bytes.NewReader(packBytes)); err != nil {
+		return err
+	}
+
+	branch := "refs/heads/main"
+	for name, sha := range refs {
+		if name == "HEAD" {
+			continue
+		}
+		if err := updateRef(name, sha); err != nil {
+			return err
+		}
+		if sha == head && strings.HasPrefix(name, "refs/heads/") {
+			branch = name
+		}
+	}
+
+	headContents := fmt.Sprintf("ref: %s\n", branch)
+	if err := os.WriteFile(".git/HEAD", []byte(headContents), 0644); err != nil {
+		return err
+	}
+
+	treeHash, err := resolveTreeHash(head)
+	if err != nil {
+		return err
+	}
+
+	return restoreTree(treeHash, ".", checkoutOptions{Force: true})
+}
+
+// wantedHashes collects the distinct commit hashes advertised by the
+// server's ref list, excluding the synthetic "HEAD" entry.
+func wantedHashes(refs map[string]string) []string {
+	seen := make(map[string]bool, len(refs))
+	var hashes []string
+
+	for name, sha := range refs {
+		if name == "HEAD" || seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		hashes = append(hashes, sha)
+	}
+
+	return hashes
+}
+
+// discoverRefs performs the first half of the smart HTTP handshake —
+// GET <url>/info/refs?service=git-upload-pack — and returns every
+// advertised ref as a map from ref name (plus the synthetic "HEAD") to
+// commit sha.
+func discoverRefs(url string) (map[string]string, error) {
+	resp, err := http.Get(url + "/info/refs?service=git-upload-pack")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET info/refs: unexpected status %s", resp.Status)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	refs := make(map[string]string)
+	sawServiceLine := false
+
+	for {
+		line, err := readPktLine(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == nil {
+			continue // flush packet
+		}
+
+		text := strings.TrimRight(string(line), "\n")
+		if !sawServiceLine {
+			sawServiceLine = true
+			if strings.HasPrefix(text, "# service=") {
+				continue
+			}
+		}
+
+		// ref lines are "<sha> <refname>\x00<capabilities>"
+		if i := strings.IndexByte(text, 0); i != -1 {
+			text = text[:i]
+		}
+
+		sha, name, found := strings.Cut(text, " ")
+		if !found {
+			continue
+		}
+
+		refs[name] = sha
+	}
+
+	return refs, nil
+}
+
+// fetchPack performs the second half of the smart HTTP handshake: POST
+// <url>/git-upload-pack with a pkt-line "want" list, then demultiplex the
+// sideband response to recover the raw packfile bytes.
+func fetchPack(url string, wants []string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url+"/git-upload-pack", bytes.NewReader(buildWantRequest(wants)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-git-upload-pack-request")
+	req.Header.Set("Accept", "application/x-git-upload-pack-result")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("POST git-upload-pack: unexpected status %s", resp.Status)
+	}
+
+	return demuxSideband(bufio.NewReader(resp.Body))
+}
+
+// buildWantRequest renders the request body for git-upload-pack: one
+// "want <sha>" pkt-line per wanted commit (capabilities attached to the
+// first), a flush packet, and "done".
+func buildWantRequest(wants []string) []byte {
+	var buf bytes.Buffer
+
+	for i, sha := range wants {
+		if i == 0 {
+			buf.WriteString(pktLine(fmt.Sprintf("want %s multi_ack_detailed side-band-64k ofs-delta\n", sha)))
+		} else {
+			buf.WriteString(pktLine(fmt.Sprintf("want %s\n", sha)))
+		}
+	}
+
+	buf.WriteString("0000")
+	buf.WriteString(pktLine("done\n"))
+
+	return buf.Bytes()
+}
+
+// demuxSideband reads pkt-lines from a side-band-64k response, routing
+// channel 1 (pack data) into the returned buffer, discarding channel 2
+// (progress messages), and failing on channel 3 (errors).
+func demuxSideband(r *bufio.Reader) ([]byte, error) {
+	var pack bytes.Buffer
+
+	for {
+		line, err := readPktLine(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == nil || len(line) == 0 {
+			continue
+		}
+
+		switch line[0] {
+		case 1:
+			pack.Write(line[1:])
+		case 2:
+			// progress message; nothing to do with it here
+		case 3:
+			return nil, fmt.Errorf("remote error: %s", line[1:])
+		default:
+			// not a sideband data frame (e.g. the leading "NAK\n")
+		}
+	}
+
+	return pack.Bytes(), nil
+}
+
+// pktLine encodes data as a Git pkt-line: a 4-hex-digit length prefix
+// (including itself) followed by the payload. An empty string produces
+// the flush packet "0000".
+func pktLine(data string) string {
+	if data == "" {
+		return "0000"
+	}
+	return fmt.Sprintf("%04x%s", len(data)+4, data)
+}
+
+// readPktLine reads one pkt-line from r, returning nil for a flush packet
+// and io.EOF once the stream is exhausted.
+func readPktLine(r *bufio.Reader) ([]byte, error) {
+	var lenHex [4]byte
+	if _, err := io.ReadFull(r, lenHex[:]); err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.ParseInt(string(lenHex[:]), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pkt-line length %q: %w", lenHex, err)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
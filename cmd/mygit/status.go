@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// headTreeHash resolves the tree of the commit HEAD currently points at,
+// or "" if there isn't one yet (a repo with no commits).
+func headTreeHash() (string, error) {
+	branchRef, err := currentBranchRef()
+	if err != nil {
+		return "", err
+	}
+
+	commitHash, err := readRef(branchRef)
+	if err != nil {
+		return "", err
+	}
+	if commitHash == "" {
+		return "", nil
+	}
+
+	return resolveTreeHash(commitHash)
+}
+
+// worktreeState loads the three sources a status/diff walk compares:
+// HEAD's tree, the index, and the working directory.
+func worktreeState() (head, index, work []FileState, err error) {
+	treeHash, err := headTreeHash()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	head, err = flattenTree(treeHash, "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	indexEntries, err := readIndex()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	index = flattenIndex(indexEntries)
+
+	cache, err := loadFileCache()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	work, err = flattenWorkingDir(cache, defaultChunkThreshold)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return head, index, work, nil
+}
+
+// status walks HEAD, the index, and the working directory together and
+// reports what's staged (HEAD vs index), what's still unstaged (index vs
+// working directory), and what's untracked.
+func status(args []string) error {
+	head, index, work, err := worktreeState()
+	if err != nil {
+		return err
+	}
+
+	entries := walkMerkleTrie(head, index, work)
+
+	var staged, unstaged, untracked []string
+	for _, e := range entries {
+		if e.Head == nil && e.Index == nil && e.Work != nil {
+			untracked = append(untracked, e.Path)
+			continue
+		}
+
+		if k := classify(e.Head, e.Index); k != unmodified {
+			staged = append(staged, fmt.Sprintf("\t%s: %s", k, e.Path))
+		}
+		if k := classify(e.Index, e.Work); k != unmodified {
+			unstaged = append(unstaged, fmt.Sprintf("\t%s: %s", k, e.Path))
+		}
+	}
+
+	if len(staged) == 0 && len(unstaged) == 0 && len(untracked) == 0 {
+		fmt.Println("nothing to commit, working tree clean")
+		return nil
+	}
+
+	if len(staged) > 0 {
+		fmt.Println("Changes to be committed:")
+		fmt.Println(strings.Join(staged, "\n"))
+		fmt.Println()
+	}
+	if len(unstaged) > 0 {
+		fmt.Println("Changes not staged for commit:")
+		fmt.Println(strings.Join(unstaged, "\n"))
+		fmt.Println()
+	}
+	if len(untracked) > 0 {
+		fmt.Println("Untracked files:")
+		for _, path := range untracked {
+			fmt.Printf("\t%s\n", path)
+		}
+	}
+
+	return nil
+}
+
+// readBlobContents reads the content of a blob or chunked-blob object,
+// reassembling chunked blobs transparently.
+func readBlobContents(hash string) ([]byte, error) {
+	kind, body, err := readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "blob":
+		return body, nil
+	case "chunked-blob":
+		return reassembleChunkedBlob(body)
+	default:
+		return nil, fmt.Errorf("object %s is a %s, not a blob", hash, kind)
+	}
+}
+
+// isBinary guesses whether data is binary: it contains a NUL byte.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// diff prints a unified patch for every path the working directory
+// changed relative to the index (or a "Binary files differ" notice for
+// non-text content).
+func diff(args []string) error {
+	_, index, work, err := worktreeState()
+	if err != nil {
+		return err
+	}
+
+	entries := walkMerkleTrie(nil, index, work)
+	slices.SortFunc(entries, func(a, b mergeEntry) int { return strings.Compare(a.Path, b.Path) })
+
+	for _, e := range entries {
+		if classify(e.Index, e.Work) == unmodified {
+			continue
+		}
+
+		var oldContent, newContent []byte
+		if e.Index != nil {
+			oldContent, err = readBlobContents(e.Index.Hash)
+			if err != nil {
+				return err
+			}
+		}
+		if e.Work != nil {
+			newContent, err = os.ReadFile(e.Path)
+			if err != nil {
+				return err
+			}
+		}
+
+		if isBinary(oldContent) || isBinary(newContent) {
+			fmt.Printf("Binary files a/%s and b/%s differ\n", e.Path, e.Path)
+			continue
+		}
+
+		fmt.Print(unifiedDiff(e.Path, e.Path, oldContent, newContent))
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// TestStatusCleanAfterCommitNestedDirs checks that a freshly committed
+// tree with nested directories reports no changes. This was the symptom
+// of the createTreeFromDir entry-naming bug: flattenTree joined the
+// (wrongly) path-prefixed entry name onto the already-descended prefix,
+// so every nested file showed up under both its real path (as
+// "deleted") and a duplicated-prefix path (as "untracked").
+func TestStatusCleanAfterCommitNestedDirs(t *testing.T) {
+	chdirTempRepo(t)
+	makeNestedFile(t)
+
+	if err := commit([]string{"-m", "add nested file"}); err != nil {
+		t.Fatal(err)
+	}
+
+	head, index, work, err := worktreeState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := walkMerkleTrie(head, index, work)
+	for _, e := range entries {
+		if classify(e.Head, e.Index) != unmodified {
+			t.Fatalf("path %s: expected unmodified (head vs index), got a diff", e.Path)
+		}
+		if e.Head == nil && e.Index == nil && e.Work != nil {
+			t.Fatalf("path %s: reported untracked right after commit", e.Path)
+		}
+	}
+}
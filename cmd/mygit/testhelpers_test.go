@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdirTempRepo creates a temp dir, chdirs into it, initializes a mygit
+// repo there, and restores the original working directory when the test
+// ends. Several tests need an isolated repo to write-tree/commit into.
+func chdirTempRepo(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(oldWd) })
+
+	if err := initRepo(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// makeNestedFile creates a small file three directories deep in the
+// current directory, the fixture several tests use to stand in for "a
+// repo with nesting beyond one level". It returns the directory it
+// created, the file's path relative to the current directory, and the
+// file's contents.
+func makeNestedFile(t *testing.T) (dir, relPath string, contents []byte) {
+	t.Helper()
+
+	dir = filepath.Join("src", "pkg", "util")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	contents = []byte("package util\n")
+	relPath = filepath.Join(dir, "helper.go")
+	if err := os.WriteFile(relPath, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, relPath, contents
+}
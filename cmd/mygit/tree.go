@@ -20,6 +20,20 @@ const (
 	ObjectModeDir                = "40000"
 )
 
+// objectModeFromFileInfo derives the tree entry mode mygit would store
+// for a working-tree file from its (Lstat'd) os.FileInfo: a symlink, an
+// executable, or a plain file.
+func objectModeFromFileInfo(info os.FileInfo) ObjectMode {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return ObjectModeSymlink
+	case info.Mode()&0111 != 0:
+		return ObjectModeExe
+	default:
+		return ObjectModeFile
+	}
+}
+
 type ObjKind int
 
 const (
@@ -30,11 +44,10 @@ const (
 
 type Object struct {
 	Kind     ObjKind
-	// when including commits,
-	// this will be a "tagged union":
-	// with fields `.Commit`, `.Tree`, and `.Blob` fields.
-	// the `.Tree` can have `.Entries`,
-	// and `.Commit` can have `.Message` and `.Author`, etc.
+	// this is a "tagged union": `.Commit` is only populated when
+	// `Kind == ObjKindCommit`. `.Tree` (with `.Entries`) and `.Blob`
+	// will join it similarly, eventually.
+	Commit   *CommitObject
 	ShaHash  []byte
 	FileName string
 	Mode     ObjectMode
@@ -148,7 +161,7 @@ func serializeTreeObject(entries []*Object) []byte {
 
 // From a directory path, create a tree object.
 // This object is also written to a file on disk.
-func createTreeFromDir(dir string) (*Object, error) {
+func createTreeFromDir(dir string, cache CacheContext, chunkThreshold int64) (*Object, error) {
 	var entries []*Object
 	dir = filepath.Clean(dir)
 
@@ -172,7 +185,7 @@ func createTreeFromDir(dir string) (*Object, error) {
 		}
 
 		if f.IsDir() {
-			entry, err := createTreeFromDir(path)
+			entry, err := createTreeFromDir(path, cache, chunkThreshold)
 			if err != nil {
 				return err
 			}
@@ -184,7 +197,7 @@ func createTreeFromDir(dir string) (*Object, error) {
 			return filepath.SkipDir
 		}
 
-		object, err := createObjectFromFile(path)
+		object, err := createObjectFromFile(path, cache, chunkThreshold)
 		if err != nil {
 			return err
 		}
@@ -211,7 +224,7 @@ func createTreeFromDir(dir string) (*Object, error) {
 
 	tree := &Object{
 		ShaHash:  hash[:],
-		FileName: dir,
+		FileName: filepath.Base(dir),
 		Mode:     ObjectModeDir,
 	}
 
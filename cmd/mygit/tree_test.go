@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// TestCreateTreeFromDirNestedEntryNames checks that a subtree more than
+// one level deep is serialized into its parent tree under its own
+// basename, not the full path accumulated while recursing.
+func TestCreateTreeFromDirNestedEntryNames(t *testing.T) {
+	chdirTempRepo(t)
+	_, relPath, _ := makeNestedFile(t)
+
+	cache, err := loadFileCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := createTreeFromDir(".", cache, defaultChunkThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flattened, err := flattenTree(fmt.Sprintf("%x", tree.ShaHash), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotPaths []string
+	for _, f := range flattened {
+		gotPaths = append(gotPaths, f.Path)
+	}
+
+	want := filepath.ToSlash(relPath)
+	found := false
+	for _, p := range gotPaths {
+		if p == want {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("flattened tree paths = %v, want entry %q", gotPaths, want)
+	}
+}